@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeAtomic replaces path's contents durably. write's output is built in
+// a temp file created in path's own directory (guaranteeing the final
+// rename is same-filesystem), fsynced (unless fsync is false) and closed,
+// then renamed over path. Unless fsync is false, the parent directory is
+// fsynced too afterwards, so
+// the rename itself survives a crash and not just the file's bytes. If
+// write returns an error, the temp file is removed and path is untouched.
+func writeAtomic(path string, fsync bool, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tempFile, err := os.CreateTemp(dir, ".logcleaner-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	if err := tempFile.Chmod(0644); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	writer := bufio.NewWriter(tempFile)
+	if err := write(writer); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if fsync {
+		if err := tempFile.Sync(); err != nil {
+			tempFile.Close()
+			os.Remove(tempPath)
+			return err
+		}
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if fsync {
+		if err := fsyncDir(dir); err != nil {
+			return fmt.Errorf("fsync directory %q: %w", dir, err)
+		}
+	}
+	return nil
+}
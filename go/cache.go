@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fingerprint captures enough about a log file's on-disk state to tell
+// whether it changed since the last run, without re-reading the whole file.
+type fingerprint struct {
+	Size      int64  `json:"size"`
+	ModTime   int64  `json:"mtime"`
+	Hash      string `json:"hash"`
+	LineCount int    `json:"line_count"`
+}
+
+// cacheStore is a JSON file under $XDG_CACHE_HOME/logcleaner/, keyed by
+// absolute log path, recording each file's fingerprint as of the end of its
+// last successful (non-dry-run) clean.
+type cacheStore struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]fingerprint
+}
+
+func cacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "logcleaner"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "logcleaner"), nil
+}
+
+func cacheFilePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache.json"), nil
+}
+
+func loadCache() (*cacheStore, error) {
+	path, err := cacheFilePath()
+	if err != nil {
+		return nil, err
+	}
+	store := &cacheStore{path: path, entries: map[string]fingerprint{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func clearCacheFile() error {
+	path, err := cacheFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// cacheKey canonicalizes path to an absolute path so the same log is keyed
+// identically regardless of the cwd or spelling it was invoked with (e.g.
+// "./app.log" from one directory and "./sub/app.log" from its parent both
+// resolve to the same key). Falls back to path unchanged on the rare error
+// (e.g. cwd has been removed), so the cache still works, just non-portably.
+func cacheKey(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+func (c *cacheStore) get(path string) (fingerprint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fp, ok := c.entries[cacheKey(path)]
+	return fp, ok
+}
+
+func (c *cacheStore) set(path string, fp fingerprint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[cacheKey(path)] = fp
+}
+
+func (c *cacheStore) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// fileFingerprint fingerprints the file at path using its size, mtime, and a
+// sha1 of its first and last 64KB, so multi-GB logs don't need a full read
+// just to detect that nothing changed.
+func fileFingerprint(path string, lineCount int) (fingerprint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fingerprint{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fingerprint{}, err
+	}
+	defer f.Close()
+
+	const chunkSize = 64 * 1024
+	h := sha1.New()
+
+	head := make([]byte, chunkSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fingerprint{}, err
+	}
+	h.Write(head[:n])
+
+	if info.Size() > chunkSize {
+		if _, err := f.Seek(info.Size()-chunkSize, io.SeekStart); err != nil {
+			return fingerprint{}, err
+		}
+		tail := make([]byte, chunkSize)
+		n, err = io.ReadFull(f, tail)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return fingerprint{}, err
+		}
+		h.Write(tail[:n])
+	}
+
+	return fingerprint{
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UnixNano(),
+		Hash:      fmt.Sprintf("%x", h.Sum(nil)),
+		LineCount: lineCount,
+	}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// fsyncDir fsyncs the directory at path, so a prior create/rename within it
+// is durable across a crash and not just visible to the current process.
+func fsyncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
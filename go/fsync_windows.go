@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+// fsyncDir is a no-op on Windows: there is no portable way to open a
+// directory and sync it the way POSIX filesystems allow, and NTFS does not
+// need it for rename durability the way ext4/xfs do.
+func fsyncDir(path string) error {
+	return nil
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -46,7 +50,10 @@ var helpTemplate = fmt.Sprintf(`
 {{.Example}}{{end}}
 `, ColorBold, "LOGCLEANER", VERSION, ColorReset, ColorYellow, ColorReset, ColorYellow, ColorReset, ColorYellow, ColorReset, ColorYellow, ColorReset)
 
-func copyFile(src, dst string) error {
+// copyFile copies src to dst. When fsync is true, dst is synced before close
+// and its parent directory is synced afterwards, so the backup it produces
+// survives a crash rather than being lost along with the original.
+func copyFile(src, dst string, fsync bool) error {
 	source, err := os.Open(src)
 	if err != nil {
 		return err
@@ -56,163 +63,509 @@ func copyFile(src, dst string) error {
 	if err != nil {
 		return err
 	}
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+	if fsync {
+		if err := destination.Sync(); err != nil {
+			destination.Close()
+			return err
+		}
+	}
+	if err := destination.Close(); err != nil {
+		return err
+	}
+	if fsync {
+		return fsyncDir(filepath.Dir(dst))
+	}
+	return nil
+}
+
+// compressFile gzip-compresses src into dst, streaming through io.Copy
+// rather than buffering the whole file in memory. See copyFile for the
+// fsync semantics.
+func compressFile(src, dst string, fsync bool) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(destination)
+	if _, err := io.Copy(gz, source); err != nil {
+		gz.Close()
+		destination.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		destination.Close()
+		return err
+	}
+	if fsync {
+		if err := destination.Sync(); err != nil {
+			destination.Close()
+			return err
+		}
+	}
+	if err := destination.Close(); err != nil {
+		return err
+	}
+	if fsync {
+		return fsyncDir(filepath.Dir(dst))
+	}
+	return nil
+}
+
+// decompressFile reverses compressFile, used to restore a gzipped backup
+// onto the original path during rollback.
+func decompressFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	gz, err := gzip.NewReader(source)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	destination, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
 	defer destination.Close()
-	_, err = io.Copy(destination, source)
+	_, err = io.Copy(destination, gz)
 	return err
 }
 
-func rollback(originalPath, backupPath string) {
-	fmt.Printf("%srollback: restoring %s%s\n", ColorBlue, originalPath, ColorReset)
-	os.Rename(backupPath, originalPath)
+// backupPathFor builds the timestamped backup path for path, appending a
+// ".gz" suffix when the backup is gzip-compressed.
+func backupPathFor(path string, compressed bool) string {
+	backupPath := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("2006-01-02-15-04-05"))
+	if compressed {
+		backupPath += ".gz"
+	}
+	return backupPath
 }
 
-type CleanOptions struct {
-	Path       string
-	MaxRows    int
-	MinDateStr string
-	DateFormat string
-	Exclude    []string
+func createBackup(src, dst string, compressed, fsync bool) error {
+	if compressed {
+		return compressFile(src, dst, fsync)
+	}
+	return copyFile(src, dst, fsync)
 }
 
-func cleanLog(opts CleanOptions) error {
-	if opts.MinDateStr == "" {
-		opts.DateFormat = ""
+func rollback(originalPath, backupPath string, compressed bool) {
+	fmt.Printf("%srollback: restoring %s%s\n", ColorBlue, originalPath, ColorReset)
+	if !compressed {
+		os.Rename(backupPath, originalPath)
+		return
 	}
+	if err := decompressFile(backupPath, originalPath); err != nil {
+		fmt.Printf("%srollback failed: %v%s\n", ColorRed, err, ColorReset)
+		return
+	}
+	os.Remove(backupPath)
+}
 
-	fmt.Printf("%s[INFO] Cleaning log: %s (max lines: %d)%s\n", ColorBold, opts.Path, opts.MaxRows, ColorReset)
+// cacheHit reports whether opts.Path is unchanged since the cache's last
+// recorded post-clean fingerprint, printing the same [SKIP] message used by
+// both cleanLog and cleanStructuredLog when it is.
+func cacheHit(opts CleanOptions) bool {
+	if opts.Cache == nil {
+		return false
+	}
+	fp, err := fileFingerprint(opts.Path, -1)
+	if err != nil {
+		return false
+	}
+	cached, ok := opts.Cache.get(opts.Path)
+	if !ok || cached.Size != fp.Size || cached.ModTime != fp.ModTime || cached.Hash != fp.Hash {
+		return false
+	}
+	fmt.Printf("%s[SKIP] unchanged: %s%s\n", ColorDim, opts.Path, ColorReset)
+	return true
+}
+
+// prepareBackup creates opts.Path's backup, unless opts.DryRun (there is
+// nothing to protect a file we are not going to rewrite). It returns the
+// path cleanLog/cleanStructuredLog should read from, and the backup's path
+// (empty when none was made, which also means there's nothing to roll back).
+func prepareBackup(opts CleanOptions) (readPath, backupPath string, err error) {
+	if opts.DryRun {
+		return opts.Path, "", nil
+	}
+	backupPath = backupPathFor(opts.Path, opts.CompressBackup)
+	if err := createBackup(opts.Path, backupPath, opts.CompressBackup, opts.Fsync); err != nil {
+		return "", "", fmt.Errorf("backup failed: %w", err)
+	}
+	return backupPath, backupPath, nil
+}
 
-	// 1. Backup
-	backupPath := fmt.Sprintf("%s.%s.bak", opts.Path, time.Now().Format("2006-01-02-15-04-05"))
-	if err := copyFile(opts.Path, backupPath); err != nil {
-		return fmt.Errorf("backup failed: %w", err)
+// rollbackGuard returns a func suitable for `defer` that restores path from
+// backupPath when *failed is true by the time the deferring function
+// returns. backupPath == "" (set by prepareBackup in --dry-run mode) makes
+// it a no-op.
+func rollbackGuard(path, backupPath string, compressed bool, failed *bool) func() {
+	return func() {
+		if *failed && backupPath != "" {
+			rollback(path, backupPath, compressed)
+		}
 	}
+}
 
-	var operationFailed bool
-	defer func() {
-		if operationFailed {
-			rollback(opts.Path, backupPath)
+// finishWrite prunes old backups and records the cache fingerprint after a
+// successful write, the common tail of cleanLog and cleanStructuredLog.
+func finishWrite(opts CleanOptions, entryCount int) {
+	pruneBackups(opts.Path, opts.KeepBackups)
+	if opts.Cache != nil {
+		if fp, err := fileFingerprint(opts.Path, entryCount); err == nil {
+			opts.Cache.set(opts.Path, fp)
 		}
-	}()
+	}
+}
 
-	file, err := os.Open(backupPath)
+// backupSuffixPattern matches the "<timestamp>.bak" or "<timestamp>.bak.gz"
+// suffix backupPathFor appends after "<path>.", anchoring pruneBackups'
+// glob results to backups of path itself rather than of a sibling like
+// "<path>.1".
+var backupSuffixPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}\.bak(\.gz)?$`)
+
+// pruneBackups removes the oldest timestamped backups of path beyond the
+// most recent keep, covering both plain ".bak" and gzipped ".bak.gz" files.
+// keep <= 0 means unlimited, i.e. nothing is pruned.
+//
+// The glob alone (path + ".*.bak*") is not anchored to path: for a file
+// named "app.log" it also matches "app.log.1.<ts>.bak", a backup that
+// belongs to a sibling rotated log. Matches are filtered down to those whose
+// remainder after "<path>." is exactly "<timestamp>.bak" or
+// "<timestamp>.bak.gz" (backupSuffixPattern), i.e. no extra "."-separated
+// segment sits between path and the timestamp.
+func pruneBackups(path string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	candidates, err := filepath.Glob(path + ".*.bak*")
 	if err != nil {
-		operationFailed = true
-		return err
+		return
 	}
-	defer file.Close()
+	prefix := path + "."
+	matches := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		rest := strings.TrimPrefix(c, prefix)
+		if rest == c || !backupSuffixPattern.MatchString(rest) {
+			continue
+		}
+		matches = append(matches, c)
+	}
+	if len(matches) <= keep {
+		return
+	}
+	// Backups are named with a sortable timestamp, so lexical order is
+	// chronological order.
+	sort.Strings(matches)
+	for _, old := range matches[:len(matches)-keep] {
+		if err := os.Remove(old); err != nil {
+			fmt.Printf("%s[WARN] could not prune old backup %s: %v%s\n", ColorYellow, old, err, ColorReset)
+		}
+	}
+}
 
-	var rawLines []string
-	scanner := bufio.NewScanner(file)
-	// Support for long lines up to 10MB
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024)
+// compileAll compiles each RE2 pattern once, up front, so cleanLog only
+// ever matches against already-compiled regexes.
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
 
-	for scanner.Scan() {
-		rawLines = append(rawLines, scanner.Text())
+type CleanOptions struct {
+	Path           string
+	MaxRows        int
+	MinDateStr     string
+	DateFormat     string
+	Exclude        []string
+	Include        []*regexp.Regexp
+	ExcludeRegex   []*regexp.Regexp
+	DateRegex      *regexp.Regexp
+	CompressBackup bool
+	KeepBackups    int
+	DryRun         bool
+	Cache          *cacheStore
+	Fsync          bool
+
+	// Structured-log mode (--input-format json/logfmt). When Parser is set,
+	// cleanLog delegates to cleanStructuredLog instead of the plain-text
+	// pipeline above.
+	Parser       Parser
+	TimeField    string
+	LevelField   string
+	MinLevel     string
+	Where        []wherePredicate
+	WhereRegex   []whereRegexPredicate
+	OutputFormat string
+}
+
+// entryTimestamp returns the substring of entry that should be parsed as a
+// timestamp. When opts.DateRegex is set, the timestamp is located via its
+// named "ts" capture group (falling back to the first capture group), which
+// lets it appear anywhere in the line instead of only as a fixed-width
+// prefix. Otherwise it falls back to the legacy prefix slicing.
+func entryTimestamp(entry string, opts CleanOptions, formatLen int) (string, bool) {
+	if opts.DateRegex != nil {
+		match := opts.DateRegex.FindStringSubmatch(entry)
+		if match == nil {
+			return "", false
+		}
+		for i, name := range opts.DateRegex.SubexpNames() {
+			if name == "ts" && i < len(match) {
+				return match[i], true
+			}
+		}
+		if len(match) > 1 {
+			return match[1], true
+		}
+		return match[0], true
+	}
+	if len(entry) >= formatLen {
+		return entry[:formatLen], true
 	}
+	return "", false
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("%s[ERROR] Read failed: %v%s\n", ColorRed, err, ColorReset)
-		operationFailed = true
-		return err
+func cleanLog(opts CleanOptions) error {
+	if opts.Parser != nil {
+		return cleanStructuredLog(opts)
 	}
 
-	if len(rawLines) == 0 {
+	// Both the tail fast path and the full-scan path below group multiline
+	// entries by date format/regex even when no --date threshold is given
+	// (a MaxRows-only run still shouldn't split a multiline entry apart).
+	// Capture them before the long-standing rule just below — no threshold
+	// means no threshold-filtering format either — clears them from opts.
+	groupingFormat := opts.DateFormat
+	groupingRegex := opts.DateRegex
+
+	if opts.MinDateStr == "" {
+		opts.DateFormat = ""
+		opts.DateRegex = nil
+	}
+
+	fmt.Printf("%s[INFO] Cleaning log: %s (max lines: %d)%s\n", ColorBold, opts.Path, opts.MaxRows, ColorReset)
+
+	if cacheHit(opts) {
 		return nil
 	}
 
-	// 2. Multiline grouping
+	readPath, backupPath, err := prepareBackup(opts)
+	if err != nil {
+		return err
+	}
+
+	var operationFailed bool
+	defer rollbackGuard(opts.Path, backupPath, opts.CompressBackup, &operationFailed)()
+
+	// The tail-only fast path avoids reading the whole file: it only
+	// applies when there's no content filtering and no date threshold (both
+	// require inspecting every line), when readPath is a plain,
+	// randomly-seekable file (not a gzip-compressed backup), and when
+	// grouping (if any) is prefix-based — tailEntries has no equivalent of
+	// entryTimestamp's --date-regex support, so a regex-grouped log falls
+	// back to the full scan instead of silently grouping by prefix.
+	isCompressedRead := !opts.DryRun && opts.CompressBackup
+	fastPathEligible := opts.MinDateStr == "" &&
+		len(opts.Exclude) == 0 && len(opts.ExcludeRegex) == 0 && len(opts.Include) == 0 &&
+		!isCompressedRead && groupingRegex == nil
+
 	var processedLines []string
-	formatLen := len(opts.DateFormat)
-
-	if formatLen > 0 {
-		for _, line := range rawLines {
-			isNewEntry := false
-			if len(line) >= formatLen {
-				prefix := line[:formatLen]
-				if _, err := time.Parse(opts.DateFormat, prefix); err == nil {
-					isNewEntry = true
-				}
-			}
+	var totalRawLines int
+	usedFastPath := false
 
-			if isNewEntry || len(processedLines) == 0 {
-				processedLines = append(processedLines, line)
-			} else {
-				lastIdx := len(processedLines) - 1
-				// Join with space to keep it single-line in the file
-				processedLines[lastIdx] = processedLines[lastIdx] + " " + line
-			}
+	if fastPathEligible {
+		tailed, err := tailEntries(readPath, opts.MaxRows, groupingFormat)
+		if err != nil {
+			operationFailed = true
+			return err
+		}
+		if len(tailed) == 0 {
+			return nil
 		}
+		processedLines = tailed
+		usedFastPath = true
 	} else {
-		processedLines = rawLines
-	}
+		file, err := os.Open(readPath)
+		if err != nil {
+			operationFailed = true
+			return err
+		}
+		defer file.Close()
+
+		var reader io.Reader = file
+		if isCompressedRead {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				operationFailed = true
+				return err
+			}
+			defer gz.Close()
+			reader = gz
+		}
 
-	// 3. Filter by content
-	if len(opts.Exclude) > 0 {
-		var filtered []string
-		for _, entry := range processedLines {
-			match := false
-			for _, f := range opts.Exclude {
-				if strings.Contains(entry, f) {
-					match = true
-					break
+		var rawLines []string
+		scanner := bufio.NewScanner(reader)
+		// Support for long lines up to 10MB
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 10*1024*1024)
+
+		for scanner.Scan() {
+			rawLines = append(rawLines, scanner.Text())
+		}
+
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("%s[ERROR] Read failed: %v%s\n", ColorRed, err, ColorReset)
+			operationFailed = true
+			return err
+		}
+
+		if len(rawLines) == 0 {
+			return nil
+		}
+		totalRawLines = len(rawLines)
+
+		// 2. Multiline grouping. This uses groupingFormat/groupingRegex
+		// rather than opts.DateFormat/opts.DateRegex so that a MaxRows-only
+		// run (MinDateStr == "") still groups multiline entries, matching
+		// the tail fast path's behavior above.
+		formatLen := len(groupingFormat)
+		groupOpts := opts
+		groupOpts.DateFormat = groupingFormat
+		groupOpts.DateRegex = groupingRegex
+
+		if formatLen > 0 || groupingRegex != nil {
+			for _, line := range rawLines {
+				isNewEntry := false
+				if ts, ok := entryTimestamp(line, groupOpts, formatLen); ok {
+					if _, err := time.Parse(groupingFormat, ts); err == nil {
+						isNewEntry = true
+					}
+				}
+
+				if isNewEntry || len(processedLines) == 0 {
+					processedLines = append(processedLines, line)
+				} else {
+					lastIdx := len(processedLines) - 1
+					// Join with space to keep it single-line in the file
+					processedLines[lastIdx] = processedLines[lastIdx] + " " + line
 				}
 			}
-			if !match {
-				filtered = append(filtered, entry)
-			}
+		} else {
+			processedLines = rawLines
 		}
-		processedLines = filtered
-	}
 
-	// 4. Filter by date threshold
-	if opts.MinDateStr != "" && formatLen > 0 {
-		minDate, err := time.Parse(opts.DateFormat, opts.MinDateStr)
-		if err != nil {
-			return fmt.Errorf("could not parse --date '%s' with format '%s': %w", opts.MinDateStr, opts.DateFormat, err)
+		// 3. Filter by content
+		if len(opts.Include) > 0 || len(opts.Exclude) > 0 || len(opts.ExcludeRegex) > 0 {
+			var filtered []string
+			for _, entry := range processedLines {
+				if len(opts.Include) > 0 {
+					kept := false
+					for _, r := range opts.Include {
+						if r.MatchString(entry) {
+							kept = true
+							break
+						}
+					}
+					if !kept {
+						continue
+					}
+				}
+
+				match := false
+				for _, f := range opts.Exclude {
+					if strings.Contains(entry, f) {
+						match = true
+						break
+					}
+				}
+				if !match {
+					for _, r := range opts.ExcludeRegex {
+						if r.MatchString(entry) {
+							match = true
+							break
+						}
+					}
+				}
+				if !match {
+					filtered = append(filtered, entry)
+				}
+			}
+			processedLines = filtered
 		}
 
-		var dateFiltered []string
-		for _, entry := range processedLines {
-			if len(entry) >= formatLen {
-				prefix := entry[:formatLen]
-				d, err := time.Parse(opts.DateFormat, prefix)
-				if err == nil {
-					if !d.Before(minDate) {
-						dateFiltered = append(dateFiltered, entry)
+		// 4. Filter by date threshold
+		if opts.MinDateStr != "" && (formatLen > 0 || opts.DateRegex != nil) {
+			minDate, err := time.Parse(opts.DateFormat, opts.MinDateStr)
+			if err != nil {
+				return fmt.Errorf("could not parse --date '%s' with format '%s': %w", opts.MinDateStr, opts.DateFormat, err)
+			}
+
+			var dateFiltered []string
+			for _, entry := range processedLines {
+				if ts, ok := entryTimestamp(entry, opts, formatLen); ok {
+					d, err := time.Parse(opts.DateFormat, ts)
+					if err == nil {
+						if !d.Before(minDate) {
+							dateFiltered = append(dateFiltered, entry)
+						}
 					}
 				}
+				// If an entry doesn't carry a valid date, it's discarded when date filtering is active.
 			}
-			// If an entry doesn't start with a valid date, it's discarded when date filtering is active.
+			processedLines = dateFiltered
+		}
+
+		// 5. Trimming (Applied AFTER date filtering)
+		if len(processedLines) > opts.MaxRows {
+			processedLines = processedLines[len(processedLines)-opts.MaxRows:]
 		}
-		processedLines = dateFiltered
 	}
 
-	// 5. Trimming (Applied AFTER date filtering)
-	if len(processedLines) > opts.MaxRows {
-		processedLines = processedLines[len(processedLines)-opts.MaxRows:]
+	if opts.DryRun {
+		if usedFastPath {
+			fmt.Printf("%s[DRY-RUN] %s would keep %d entries (tail-only fast path, no changes written)%s\n", ColorYellow, opts.Path, len(processedLines), ColorReset)
+		} else {
+			fmt.Printf("%s[DRY-RUN] %s would keep %d of %d entries (no changes written)%s\n", ColorYellow, opts.Path, len(processedLines), totalRawLines, ColorReset)
+		}
+		return nil
 	}
 
 	// 6. Final Write
-	tempPath := opts.Path + ".tmp"
-	tempFile, err := os.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	err = writeAtomic(opts.Path, opts.Fsync, func(w io.Writer) error {
+		for _, entry := range processedLines {
+			if _, err := io.WriteString(w, entry+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		operationFailed = true
 		return err
 	}
 
-	writer := bufio.NewWriter(tempFile)
-	for _, entry := range processedLines {
-		writer.WriteString(entry)
-		writer.WriteString("\n")
-	}
-	writer.Flush()
-	tempFile.Close()
-
-	if err := os.Rename(tempPath, opts.Path); err != nil {
-		operationFailed = true
-		return err
-	}
+	finishWrite(opts, len(processedLines))
 
 	fmt.Printf("%s[OK] Log updated. Entries: %d%s\n", ColorGreen, len(processedLines), ColorReset)
 	return nil
@@ -220,21 +573,50 @@ func cleanLog(opts CleanOptions) error {
 
 func main() {
 	var (
-		lines   int
-		date    string
-		format  string
-		exclude []string
+		lines          int
+		date           string
+		format         string
+		exclude        []string
+		include        []string
+		excludeRegex   []string
+		dateRegex      string
+		jobs           int
+		compressBackup bool
+		keepBackups    int
+		dryRun         bool
+		noCache        bool
+		clearCache     bool
+		inputFormat    string
+		timeField      string
+		levelField     string
+		minLevel       string
+		where          []string
+		whereRegex     []string
+		outputFormat   string
+		fsync          bool
 	)
 
 	var rootCmd = &cobra.Command{
-		Short:         ColorBold + "LOGCLEANER" + ColorReset + " - a fast log cleaner and optimizer",
-		Long:          ColorBold + "LOGCLEANER" + ColorReset + " - a fast log cleaner and optimizer\n\nA specialized utility to truncate and filter text log files.\nIt merges multiline entries, filters by date and content, and keeps a specified number of the last lines.",
-		Use:           "logcleaner <path> --lines <number> [--date <date> --format <layout>] [--exclude <string>]",
-		Args:          cobra.ExactArgs(1),
+		Short: ColorBold + "LOGCLEANER" + ColorReset + " - a fast log cleaner and optimizer",
+		Long:  ColorBold + "LOGCLEANER" + ColorReset + " - a fast log cleaner and optimizer\n\nA specialized utility to truncate and filter text log files.\nIt merges multiline entries, filters by date and content, and keeps a specified number of the last lines.\nThe path argument may be a single file, a directory, or a glob pattern; matches are processed with a --jobs worker pool.",
+		Use:   "logcleaner <path|glob> --lines <number> [--date <date> --format <layout>] [--exclude <string>]",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if clearCache {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Version:       VERSION,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if clearCache {
+				if err := clearCacheFile(); err != nil {
+					return fmt.Errorf("could not clear cache: %w", err)
+				}
+				fmt.Printf("%s[OK] Cache cleared.%s\n", ColorGreen, ColorReset)
+				return nil
+			}
 			if lines <= 0 {
 				return fmt.Errorf("--lines must be positive")
 			}
@@ -256,13 +638,100 @@ func main() {
 					}
 				}
 			}
-			return cleanLog(CleanOptions{
-				Path:       args[0],
-				MaxRows:    lines,
-				MinDateStr: date,
-				DateFormat: format,
-				Exclude:    exclude,
-			})
+			includeRe, err := compileAll(include)
+			if err != nil {
+				return fmt.Errorf("could not parse --include pattern: %w", err)
+			}
+			excludeRe, err := compileAll(excludeRegex)
+			if err != nil {
+				return fmt.Errorf("could not parse --exclude-regex pattern: %w", err)
+			}
+			var dateRe *regexp.Regexp
+			if dateRegex != "" {
+				dateRe, err = regexp.Compile(dateRegex)
+				if err != nil {
+					return fmt.Errorf("could not parse --date-regex pattern: %w", err)
+				}
+			}
+
+			if outputFormat != "plain" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (want plain or json)", outputFormat)
+			}
+
+			var parser Parser
+			if inputFormat != "" && inputFormat != "plain" {
+				parser, err = newParser(inputFormat, timeField, levelField)
+				if err != nil {
+					return err
+				}
+			}
+
+			wherePreds, err := parseWherePredicates(where)
+			if err != nil {
+				return err
+			}
+			whereRegexPreds, err := parseWhereRegexPredicates(whereRegex)
+			if err != nil {
+				return err
+			}
+
+			targets, err := expandTargets(args[0])
+			if err != nil {
+				return fmt.Errorf("could not resolve %q: %w", args[0], err)
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no files matched %q", args[0])
+			}
+
+			var cache *cacheStore
+			if !noCache {
+				cache, err = loadCache()
+				if err != nil {
+					return fmt.Errorf("could not load cache: %w", err)
+				}
+			}
+
+			base := CleanOptions{
+				MaxRows:        lines,
+				MinDateStr:     date,
+				DateFormat:     format,
+				Exclude:        exclude,
+				Include:        includeRe,
+				ExcludeRegex:   excludeRe,
+				DateRegex:      dateRe,
+				CompressBackup: compressBackup,
+				KeepBackups:    keepBackups,
+				DryRun:         dryRun,
+				Cache:          cache,
+				Parser:         parser,
+				TimeField:      timeField,
+				LevelField:     levelField,
+				MinLevel:       minLevel,
+				Where:          wherePreds,
+				WhereRegex:     whereRegexPreds,
+				OutputFormat:   outputFormat,
+				Fsync:          fsync,
+			}
+
+			errs := runBatch(targets, base, jobs)
+			var failed int
+			for i, err := range errs {
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "%s[ERROR] %s: %v%s\n", ColorRed, targets[i], err, ColorReset)
+				}
+			}
+
+			if cache != nil {
+				if err := cache.save(); err != nil {
+					fmt.Fprintf(os.Stderr, "%s[WARN] could not save cache: %v%s\n", ColorYellow, err, ColorReset)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d files failed", failed, len(targets))
+			}
+			return nil
 		},
 	}
 
@@ -271,7 +740,25 @@ func main() {
 	rootCmd.Flags().StringVar(&date, "date", "", "start date threshold (YYYY-MM-DD)")
 	rootCmd.Flags().StringVar(&format, "format", "", "date layout in log")
 	rootCmd.Flags().StringSliceVar(&exclude, "exclude", []string{}, "exclude entries containing these strings")
-	rootCmd.MarkFlagRequired("lines")
+	rootCmd.Flags().StringSliceVar(&include, "include", []string{}, "keep only entries matching any of these RE2 patterns")
+	rootCmd.Flags().StringSliceVar(&excludeRegex, "exclude-regex", []string{}, "exclude entries matching any of these RE2 patterns")
+	rootCmd.Flags().StringVar(&dateRegex, "date-regex", "", "RE2 pattern locating the timestamp in a line, via a 'ts' named capture group (or its first group)")
+	rootCmd.Flags().IntVar(&jobs, "jobs", 1, "number of files to process in parallel")
+	rootCmd.Flags().BoolVar(&compressBackup, "compress-backup", false, "write the .bak backup as gzip-compressed .bak.gz")
+	rootCmd.Flags().IntVar(&keepBackups, "keep-backups", 0, "keep only the N most recent backups per file (0 = keep all)")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would be trimmed without touching disk")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "bypass the unchanged-file cache for this run")
+	rootCmd.Flags().BoolVar(&clearCache, "clear-cache", false, "clear the unchanged-file cache and exit")
+	rootCmd.Flags().StringVar(&inputFormat, "input-format", "plain", "input log format: plain, json, or logfmt")
+	rootCmd.Flags().StringVar(&timeField, "time-field", "", "structured-log field holding the entry timestamp")
+	rootCmd.Flags().StringVar(&levelField, "level-field", "", "structured-log field holding the entry level")
+	rootCmd.Flags().StringVar(&minLevel, "min-level", "", "minimum level to keep: trace, debug, info, warn, error, or fatal")
+	rootCmd.Flags().StringSliceVar(&where, "where", []string{}, "keep only structured entries matching key=value (repeatable)")
+	rootCmd.Flags().StringSliceVar(&whereRegex, "where-regex", []string{}, "keep only structured entries matching key=~pattern (repeatable)")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", "plain", "output format: plain, or json to re-emit structured entries as compact JSON")
+	rootCmd.Flags().BoolVar(&fsync, "fsync", true, "fsync the temp file and its directory before treating a write as durable (--fsync=false trades durability for speed)")
+	// --lines is enforced manually in RunE (rather than via MarkFlagRequired)
+	// so that --clear-cache can run standalone without it.
 	rootCmd.Flags().SortFlags = false
 	rootCmd.PersistentFlags().SortFlags = false
 
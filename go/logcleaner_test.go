@@ -1,9 +1,13 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
@@ -41,9 +45,8 @@ func TestCleanLog_Trimming(t *testing.T) {
 	}
 
 	opts := CleanOptions{
-		Path:       logPath,
-		MaxRows:    maxRows,
-		DateFormat: "2006-01-02",
+		Path:    logPath,
+		MaxRows: maxRows,
 	}
 
 	if err := cleanLog(opts); err != nil {
@@ -338,6 +341,889 @@ func TestCleanLog_ExcludeAndDateFilter(t *testing.T) {
 	testLog(t, ColorGreen, fmt.Sprintf("✔ Exclude and date filter applied. Kept %d lines.", len(lines)))
 }
 
+// Test date filtering and multiline grouping when the timestamp is not the
+// line prefix, e.g. JSON logs, via --date-regex.
+func TestCleanLog_WithDateRegex(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_WithDateRegex ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "test.log")
+
+	content := strings.Join([]string{
+		`{"host":"app1","ts":"2025-07-01 10:00:00","msg":"starting up"}`,
+		`{"host":"app1","ts":"2025-08-01 00:17:15","msg":"request failed"}`,
+		`  at handler.go:42`,
+		`{"host":"app1","ts":"2025-11-25 21:53:32","msg":"shutting down"}`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	dateRegex := regexp.MustCompile(`"ts":"(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})"`)
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    1000,
+		MinDateStr: "2025-08-01 00:00:00",
+		DateFormat: "2006-01-02 15:04:05",
+		DateRegex:  dateRegex,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with date-regex failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	if len(lines) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 entries kept, got %d: %v", len(lines), lines))
+		return
+	}
+
+	if !strings.Contains(lines[0], "request failed") {
+		testError(t, fmt.Sprintf("✖ First kept entry mismatch: %s", lines[0]))
+	}
+	if !strings.Contains(lines[0], "at handler.go:42") {
+		testError(t, "✖ Continuation line was not merged into its preceding entry.")
+	}
+	if !strings.Contains(lines[1], "shutting down") {
+		testError(t, fmt.Sprintf("✖ Second kept entry mismatch: %s", lines[1]))
+	}
+
+	testLog(t, ColorGreen, "✔ Date-regex filter applied with mid-line timestamp extraction.")
+}
+
+// Test glob expansion and parallel processing across multiple files, plus
+// compressed backups and backup pruning.
+func TestRunBatch_GlobAndCompressedBackups(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestRunBatch_GlobAndCompressedBackups ---")
+
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("app-%d.log", i))
+		content := strings.Join([]string{"Line 1", "Line 2", "Line 3", "Line 4"}, "\n")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Could not create test file: %v", err)
+		}
+	}
+
+	targets, err := expandTargets(filepath.Join(dir, "app-*.log"))
+	if err != nil {
+		t.Fatalf("expandTargets failed: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 glob matches, got %d: %v", len(targets), targets)
+	}
+
+	base := CleanOptions{
+		MaxRows:        2,
+		CompressBackup: true,
+		KeepBackups:    1,
+	}
+
+	errs := runBatch(targets, base, 2)
+	for i, err := range errs {
+		if err != nil {
+			testError(t, fmt.Sprintf("✖ runBatch failed on %s: %v", targets[i], err))
+		}
+	}
+
+	for _, path := range targets {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Could not read cleaned file: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			testError(t, fmt.Sprintf("✖ Expected 2 lines kept in %s, got %d", path, len(lines)))
+		}
+
+		backups, _ := filepath.Glob(path + ".*.bak*")
+		if len(backups) != 1 {
+			testError(t, fmt.Sprintf("✖ Expected 1 pruned backup for %s, found %d", path, len(backups)))
+			continue
+		}
+		if !strings.HasSuffix(backups[0], ".bak.gz") {
+			testError(t, fmt.Sprintf("✖ Expected compressed backup, got %s", backups[0]))
+		}
+	}
+
+	testLog(t, ColorGreen, "✔ Glob expansion, parallel processing, compression and pruning all worked.")
+}
+
+// Test that pruning backups of a rotated "app.log" doesn't also sweep up
+// backups belonging to its sibling "app.log.1" — a logrotate-style directory
+// layout is exactly what --keep-backups is meant to run against.
+func TestPruneBackups_DoesNotCrossRotatedSiblings(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestPruneBackups_DoesNotCrossRotatedSiblings ---")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	siblingPath := filepath.Join(dir, "app.log.1")
+
+	// Three backups of the sibling, all older than path's single backup.
+	for i, ts := range []string{"2025-01-01-00-00-00", "2025-01-02-00-00-00", "2025-01-03-00-00-00"} {
+		b := fmt.Sprintf("%s.%s.bak", siblingPath, ts)
+		if err := os.WriteFile(b, []byte(fmt.Sprintf("sibling backup %d", i)), 0644); err != nil {
+			t.Fatalf("Could not create sibling backup: %v", err)
+		}
+	}
+	ownBackup := fmt.Sprintf("%s.2025-01-04-00-00-00.bak", path)
+	if err := os.WriteFile(ownBackup, []byte("own backup"), 0644); err != nil {
+		t.Fatalf("Could not create own backup: %v", err)
+	}
+
+	pruneBackups(path, 1)
+
+	siblingBackups, _ := filepath.Glob(siblingPath + ".*.bak*")
+	if len(siblingBackups) != 3 {
+		testError(t, fmt.Sprintf("✖ Expected all 3 sibling backups to survive pruning path's backups, found %d", len(siblingBackups)))
+	}
+	if _, err := os.Stat(ownBackup); err != nil {
+		testError(t, fmt.Sprintf("✖ path's own single backup should have survived (keep=1): %v", err))
+	}
+
+	testLog(t, ColorGreen, "✔ Pruning path's backups left its rotated sibling's backups untouched.")
+}
+
+// Test that --dry-run leaves the original file and leaves no backup behind.
+func TestCleanLog_DryRun(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_DryRun ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "test.log")
+	content := "Line 1\nLine 2\nLine 3\nLine 4\nLine 5"
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:    logPath,
+		MaxRows: 2,
+		DryRun:  true,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with --dry-run failed: %v", err))
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Could not read file: %v", err)
+	}
+	if string(data) != content {
+		testError(t, "✖ --dry-run modified the original file.")
+	}
+
+	backups, _ := filepath.Glob(logPath + ".*.bak*")
+	if len(backups) != 0 {
+		testError(t, fmt.Sprintf("✖ --dry-run left %d backup file(s) behind.", len(backups)))
+	}
+
+	testLog(t, ColorGreen, "✔ --dry-run left the file and backups untouched.")
+}
+
+// Test that an unchanged file is skipped on the second run, and that a
+// genuine change (new lines appended) busts the cache.
+func TestCleanLog_Cache(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_Cache ---")
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	logPath := filepath.Join(dir, "test.log")
+	content := "Line 1\nLine 2\nLine 3"
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	cache, err := loadCache()
+	if err != nil {
+		t.Fatalf("loadCache failed: %v", err)
+	}
+
+	opts := CleanOptions{Path: logPath, MaxRows: 10, Cache: cache}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("first cleanLog run failed: %v", err))
+	}
+	if err := cache.save(); err != nil {
+		t.Fatalf("cache.save failed: %v", err)
+	}
+
+	backupsAfterFirst, _ := filepath.Glob(logPath + ".*.bak*")
+	for _, b := range backupsAfterFirst {
+		os.Remove(b)
+	}
+
+	// Second run with the same cache and an unchanged file: should skip.
+	reloaded, err := loadCache()
+	if err != nil {
+		t.Fatalf("loadCache (reload) failed: %v", err)
+	}
+	if err := cleanLog(CleanOptions{Path: logPath, MaxRows: 10, Cache: reloaded}); err != nil {
+		testError(t, fmt.Sprintf("second cleanLog run failed: %v", err))
+	}
+
+	backupsAfterSecond, _ := filepath.Glob(logPath + ".*.bak*")
+	if len(backupsAfterSecond) != 0 {
+		testError(t, fmt.Sprintf("✖ Expected cache hit to skip the run, but a backup was created: %v", backupsAfterSecond))
+	} else {
+		testLog(t, ColorGreen, "✔ Unchanged file was skipped on the second run.")
+	}
+
+	// A genuine change must bust the cache.
+	appended := content + "\nLine 4"
+	if err := os.WriteFile(logPath, []byte(appended), 0644); err != nil {
+		t.Fatalf("Could not append to test file: %v", err)
+	}
+	if err := cleanLog(CleanOptions{Path: logPath, MaxRows: 10, Cache: reloaded}); err != nil {
+		testError(t, fmt.Sprintf("third cleanLog run failed: %v", err))
+	}
+	backupsAfterThird, _ := filepath.Glob(logPath + ".*.bak*")
+	if len(backupsAfterThird) != 1 {
+		testError(t, fmt.Sprintf("✖ Expected the changed file to be reprocessed, found %d backups", len(backupsAfterThird)))
+	} else {
+		testLog(t, ColorGreen, "✔ Changed file busted the cache and was reprocessed.")
+	}
+}
+
+// Test that the cache is keyed by absolute path, so a relative and an
+// absolute spelling of the same file (as a cron job invoked from varying
+// working directories might pass) share one cache entry instead of each
+// triggering a full reprocess.
+func TestCacheStore_KeyedByAbsolutePath(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCacheStore_KeyedByAbsolutePath ---")
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	logPath := filepath.Join(dir, "test.log")
+	if err := os.WriteFile(logPath, []byte("Line 1\nLine 2\n"), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	cache, err := loadCache()
+	if err != nil {
+		t.Fatalf("loadCache failed: %v", err)
+	}
+
+	relPath, err := filepath.Rel(dir, logPath)
+	if err != nil {
+		t.Fatalf("filepath.Rel failed: %v", err)
+	}
+
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd failed: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("os.Chdir failed: %v", err)
+	}
+	defer os.Chdir(origWD)
+
+	fp, err := fileFingerprint(logPath, 2)
+	if err != nil {
+		t.Fatalf("fileFingerprint failed: %v", err)
+	}
+	cache.set(relPath, fp)
+
+	if _, ok := cache.get(logPath); !ok {
+		testError(t, "✖ A fingerprint set under a relative path was not found under its absolute spelling")
+	} else {
+		testLog(t, ColorGreen, "✔ Relative and absolute spellings of the same file share a cache entry.")
+	}
+}
+
+// Test the tail-only fast path's plain trimming (no multiline grouping)
+// against a file larger than the initial chunk size, so the read starts
+// mid-file and the leading partial line has to be dropped.
+func TestTailEntries_PlainTrim(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestTailEntries_PlainTrim ---")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.log")
+
+	const total = 5000
+	var sb strings.Builder
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&sb, "line %05d filler filler filler filler filler\n", i)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	got, err := tailEntries(path, 10, "")
+	if err != nil {
+		t.Fatalf("tailEntries failed: %v", err)
+	}
+
+	if len(got) != 10 {
+		testError(t, fmt.Sprintf("✖ Expected 10 lines, got %d", len(got)))
+		return
+	}
+	for i := 0; i < 10; i++ {
+		want := fmt.Sprintf("line %05d filler filler filler filler filler", total-10+i)
+		if got[i] != want {
+			testError(t, fmt.Sprintf("✖ Line %d mismatch.\nExpected: %q\nGot:      %q", i, want, got[i]))
+		}
+	}
+
+	testLog(t, ColorGreen, "✔ Tail-only plain trim matched a full-scan trim.")
+}
+
+// Test that a multiline entry whose continuation lines push the initial
+// chunk-size window past the start of its own timestamped header still gets
+// grouped correctly, by forcing the window to widen all the way to BOF.
+func TestTailEntries_GroupingAcrossChunkBoundary(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestTailEntries_GroupingAcrossChunkBoundary ---")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.log")
+	dateFormat := "2006-01-02 15:04:05"
+
+	var sb strings.Builder
+	sb.WriteString("2025-01-01 00:00:00 big entry start\n")
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&sb, "  continuation filler line %06d\n", i)
+	}
+	sb.WriteString("2025-06-01 12:00:00 multiline entry starts here\n")
+	sb.WriteString("  continuation line one\n")
+	sb.WriteString("  continuation line two\n")
+	sb.WriteString("2025-06-01 12:00:05 final entry\n")
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	got, err := tailEntries(path, 2, dateFormat)
+	if err != nil {
+		t.Fatalf("tailEntries failed: %v", err)
+	}
+
+	if len(got) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 grouped entries, got %d: %v", len(got), got))
+		return
+	}
+
+	wantFirst := "2025-06-01 12:00:00 multiline entry starts here   continuation line one   continuation line two"
+	wantSecond := "2025-06-01 12:00:05 final entry"
+	if got[0] != wantFirst {
+		testError(t, fmt.Sprintf("✖ First entry mismatch.\nExpected: %q\nGot:      %q", wantFirst, got[0]))
+	}
+	if got[1] != wantSecond {
+		testError(t, fmt.Sprintf("✖ Second entry mismatch.\nExpected: %q\nGot:      %q", wantSecond, got[1]))
+	}
+
+	testLog(t, ColorGreen, "✔ Multiline entry spanning the chunk boundary was grouped correctly.")
+}
+
+// Test that cleanLog itself (not tailEntries directly) drives the
+// grouping-aware tail fast path when no threshold date is set: DateFormat
+// must survive into tailEntries even though MinDateStr == "", so a
+// multiline entry straddling the chunk boundary is still joined correctly.
+func TestCleanLog_TailFastPathGrouping(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_TailFastPathGrouping ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "test.log")
+	dateFormat := "2006-01-02 15:04:05"
+
+	var sb strings.Builder
+	sb.WriteString("2025-01-01 00:00:00 big entry start\n")
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&sb, "  continuation filler line %06d\n", i)
+	}
+	sb.WriteString("2025-06-01 12:00:00 multiline entry starts here\n")
+	sb.WriteString("  continuation line one\n")
+	sb.WriteString("  continuation line two\n")
+	sb.WriteString("2025-06-01 12:00:05 final entry\n")
+
+	if err := os.WriteFile(logPath, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    2,
+		DateFormat: dateFormat,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog via tail fast path failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	if len(lines) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 grouped entries, got %d: %v", len(lines), lines))
+		return
+	}
+
+	wantFirst := "2025-06-01 12:00:00 multiline entry starts here   continuation line one   continuation line two"
+	wantSecond := "2025-06-01 12:00:05 final entry"
+	if lines[0] != wantFirst {
+		testError(t, fmt.Sprintf("✖ First entry mismatch.\nExpected: %q\nGot:      %q", wantFirst, lines[0]))
+	}
+	if lines[1] != wantSecond {
+		testError(t, fmt.Sprintf("✖ Second entry mismatch.\nExpected: %q\nGot:      %q", wantSecond, lines[1]))
+	}
+
+	testLog(t, ColorGreen, "✔ cleanLog's tail fast path grouped a multiline entry with MinDateStr unset.")
+}
+
+// Test that the full-scan branch groups multiline entries the same way the
+// tail fast path does when MinDateStr == "": an --exclude that matches
+// nothing forces cleanLog off the fast path (fastPathEligible requires
+// len(Exclude) == 0), and grouping must not depend on which path ran.
+func TestCleanLog_FullScanGroupingWithoutDateThreshold(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_FullScanGroupingWithoutDateThreshold ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "test.log")
+	dateFormat := "2006-01-02 15:04:05"
+
+	content := "2025-01-01 00:00:00 first entry\n" +
+		"  continuation of first\n" +
+		"2025-01-02 00:00:00 second entry\n" +
+		"  continuation of second\n" +
+		"2025-01-03 00:00:00 third entry\n"
+
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    3,
+		DateFormat: dateFormat,
+		Exclude:    []string{"ZZZNOPE"},
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog via full-scan path failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	want := []string{
+		"2025-01-01 00:00:00 first entry   continuation of first",
+		"2025-01-02 00:00:00 second entry   continuation of second",
+		"2025-01-03 00:00:00 third entry",
+	}
+	if len(lines) != len(want) {
+		testError(t, fmt.Sprintf("✖ Expected %d grouped entries, got %d: %v", len(want), len(lines), lines))
+		return
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			testError(t, fmt.Sprintf("✖ Entry %d mismatch.\nExpected: %q\nGot:      %q", i, want[i], lines[i]))
+		}
+	}
+
+	testLog(t, ColorGreen, "✔ cleanLog's full-scan path grouped multiline entries with MinDateStr unset.")
+}
+
+// Test that a DateRegex-grouped log with no --date threshold still goes
+// through the full-scan path instead of the tail fast path: tailEntries has
+// no --date-regex support, so taking the fast path here would silently
+// group by the wrong (prefix-based) rule and merge unrelated entries.
+func TestCleanLog_DateRegexGroupingWithoutDateThreshold(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanLog_DateRegexGroupingWithoutDateThreshold ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "test.log")
+
+	content := strings.Join([]string{
+		`{"host":"app1","ts":"2025-07-01 10:00:00","msg":"starting up"}`,
+		`{"host":"app1","ts":"2025-08-01 00:17:15","msg":"request failed"}`,
+		`{"host":"app1","ts":"2025-11-25 21:53:32","msg":"shutting down"}`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	dateRegex := regexp.MustCompile(`"ts":"(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})"`)
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    1000,
+		DateFormat: "2006-01-02 15:04:05",
+		DateRegex:  dateRegex,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with date-regex and no threshold failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	if len(lines) != 3 {
+		testError(t, fmt.Sprintf("✖ Expected 3 entries kept (ungrouped), got %d: %v", len(lines), lines))
+		return
+	}
+	if !strings.Contains(lines[0], "starting up") || !strings.Contains(lines[1], "request failed") ||
+		!strings.Contains(lines[2], "shutting down") {
+		testError(t, fmt.Sprintf("✖ Unexpected entries kept: %v", lines))
+	}
+
+	testLog(t, ColorGreen, "✔ Date-regex grouping with no threshold used the full-scan path, not the tail fast path.")
+}
+
+// Benchmark full-scan vs. tail-only cleanLog on a large synthetic log,
+// demonstrating the payoff of the fast path added in tailEntries. Run with
+// `go test -bench CleanLog -run ^$` (skipped by a normal `go test`).
+func generateBenchmarkLog(b *testing.B, targetSize int64) string {
+	b.Helper()
+	dir := b.TempDir()
+	path := filepath.Join(dir, "bench.log")
+
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatalf("could not create benchmark log: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var written int64
+	for i := 0; written < targetSize; i++ {
+		line := fmt.Sprintf("%s entry %08d filler filler filler filler filler\n",
+			base.Add(time.Duration(i)*time.Second).Format("2006-01-02 15:04:05"), i)
+		n, err := w.WriteString(line)
+		if err != nil {
+			b.Fatalf("could not write benchmark log: %v", err)
+		}
+		written += int64(n)
+	}
+	return path
+}
+
+// resetForBenchmark moves the backup cleanLog just made back over path, so
+// every iteration cleans the full-size log rather than the previous
+// iteration's trimmed output.
+func resetForBenchmark(b *testing.B, path string) {
+	b.Helper()
+	backups, _ := filepath.Glob(path + ".*.bak*")
+	for _, bak := range backups {
+		if err := os.Rename(bak, path); err != nil {
+			b.Fatalf("could not restore benchmark log: %v", err)
+		}
+	}
+}
+
+func BenchmarkCleanLog_FullScan(b *testing.B) {
+	path := generateBenchmarkLog(b, 1<<30) // ~1GB
+	opts := CleanOptions{
+		Path:       path,
+		MaxRows:    1000,
+		MinDateStr: "2025-01-01 00:00:00", // forces the date-threshold full scan
+		DateFormat: "2006-01-02 15:04:05",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cleanLog(opts); err != nil {
+			b.Fatalf("cleanLog failed: %v", err)
+		}
+		resetForBenchmark(b, path)
+	}
+}
+
+func BenchmarkCleanLog_TailScan(b *testing.B) {
+	path := generateBenchmarkLog(b, 1<<30) // ~1GB
+	opts := CleanOptions{
+		Path:    path,
+		MaxRows: 1000, // no MinDateStr/Exclude, so the tail fast path applies
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cleanLog(opts); err != nil {
+			b.Fatalf("cleanLog failed: %v", err)
+		}
+		resetForBenchmark(b, path)
+	}
+}
+
+func TestCleanStructuredLog_JSONMinLevelAndWhere(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanStructuredLog_JSONMinLevelAndWhere ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.jsonl")
+
+	content := strings.Join([]string{
+		`{"ts":"2025-08-01 00:00:00","level":"info","service":"api","msg":"started"}`,
+		`{"ts":"2025-08-01 00:01:00","level":"warn","service":"api","msg":"slow query"}`,
+		`{"ts":"2025-08-01 00:02:00","level":"error","service":"worker","msg":"job failed"}`,
+		`{"ts":"2025-08-01 00:03:00","level":"error","service":"api","msg":"request failed"}`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	parser, err := newParser("json", "ts", "level")
+	if err != nil {
+		t.Fatalf("newParser failed: %v", err)
+	}
+	wherePreds, err := parseWherePredicates([]string{"service=api"})
+	if err != nil {
+		t.Fatalf("parseWherePredicates failed: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    1000,
+		Parser:     parser,
+		LevelField: "level",
+		MinLevel:   "warn",
+		Where:      wherePreds,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with structured filters failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	if len(lines) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 entries kept, got %d: %v", len(lines), lines))
+		return
+	}
+	if !strings.Contains(lines[0], "slow query") || !strings.Contains(lines[1], "request failed") {
+		testError(t, fmt.Sprintf("✖ Unexpected entries kept: %v", lines))
+	}
+
+	testLog(t, ColorGreen, "✔ Structured --min-level and --where filters applied.")
+}
+
+func TestCleanStructuredLog_LogfmtContinuation(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanStructuredLog_LogfmtContinuation ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.logfmt")
+
+	content := strings.Join([]string{
+		`ts=2025-08-01 level=info msg=started`,
+		`ts=2025-08-02 level=error msg="request failed"`,
+		`  at handler.go:42`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	parser, err := newParser("logfmt", "", "level")
+	if err != nil {
+		t.Fatalf("newParser failed: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:    logPath,
+		MaxRows: 1000,
+		Parser:  parser,
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with logfmt input failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+
+	if len(lines) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 entries kept, got %d: %v", len(lines), lines))
+		return
+	}
+	if !strings.Contains(lines[1], `msg="request failed"`) || !strings.Contains(lines[1], "handler.go:42") {
+		testError(t, fmt.Sprintf("✖ Continuation line was not merged into its preceding record's raw text: %q", lines[1]))
+	}
+
+	testLog(t, ColorGreen, "✔ Logfmt continuation lines merged into the preceding record.")
+}
+
+func TestCleanStructuredLog_JSONOutputFormat(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanStructuredLog_JSONOutputFormat ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.logfmt")
+
+	content := strings.Join([]string{
+		`ts=2025-08-01 level=info msg=started`,
+		`ts=2025-08-02 level=error msg=failed`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	parser, err := newParser("logfmt", "", "level")
+	if err != nil {
+		t.Fatalf("newParser failed: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:         logPath,
+		MaxRows:      1000,
+		Parser:       parser,
+		OutputFormat: "json",
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with --output-format json failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+	if len(lines) != 2 {
+		testError(t, fmt.Sprintf("✖ Expected 2 entries kept, got %d: %v", len(lines), lines))
+		return
+	}
+
+	var second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		testError(t, fmt.Sprintf("✖ Second entry is not valid JSON: %v (%q)", err, lines[1]))
+		return
+	}
+	if second["msg"] != "failed" {
+		testError(t, fmt.Sprintf("✖ Unexpected msg field: %v", second["msg"]))
+	}
+
+	testLog(t, ColorGreen, "✔ --output-format json re-emitted structured entries as compact JSON.")
+}
+
+// Test that --date/--format filter structured (--input-format json) entries
+// against --time-field's parsed timestamp, the same way a plain-text log is
+// filtered against its prefix/regex-extracted one.
+func TestCleanStructuredLog_DateFilter(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanStructuredLog_DateFilter ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.jsonl")
+
+	content := strings.Join([]string{
+		`{"ts":"2025-01-01 00:00:00","msg":"old"}`,
+		`{"ts":"2025-06-01 00:00:00","msg":"new"}`,
+	}, "\n")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	parser, err := newParser("json", "ts", "")
+	if err != nil {
+		t.Fatalf("newParser failed: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    1000,
+		Parser:     parser,
+		TimeField:  "ts",
+		MinDateStr: "2025-03-01 00:00:00",
+		DateFormat: "2006-01-02 15:04:05",
+	}
+	if err := cleanLog(opts); err != nil {
+		testError(t, fmt.Sprintf("cleanLog with structured --date filter failed: %v", err))
+	}
+
+	cleanedContent, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(cleanedContent)), "\n")
+	if len(lines) != 1 {
+		testError(t, fmt.Sprintf("✖ Expected 1 entry kept, got %d: %v", len(lines), lines))
+		return
+	}
+	if !strings.Contains(lines[0], `"new"`) {
+		testError(t, fmt.Sprintf("✖ Expected the entry newer than --date to survive, got: %v", lines[0]))
+	}
+
+	testLog(t, ColorGreen, "✔ Structured --date filter dropped the entry older than the threshold.")
+}
+
+// Test that --date without --time-field is rejected in structured mode
+// rather than silently keeping (or dropping) every entry.
+func TestCleanStructuredLog_DateFilterRequiresTimeField(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestCleanStructuredLog_DateFilterRequiresTimeField ---")
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.jsonl")
+
+	if err := os.WriteFile(logPath, []byte(`{"msg":"hello"}`), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	parser, err := newParser("json", "", "")
+	if err != nil {
+		t.Fatalf("newParser failed: %v", err)
+	}
+
+	opts := CleanOptions{
+		Path:       logPath,
+		MaxRows:    1000,
+		Parser:     parser,
+		MinDateStr: "2025-03-01 00:00:00",
+		DateFormat: "2006-01-02 15:04:05",
+	}
+	if err := cleanLog(opts); err == nil {
+		testError(t, "✖ Expected an error when --date is set without --time-field in structured mode")
+	} else {
+		testLog(t, ColorGreen, "✔ --date without --time-field was rejected: "+err.Error())
+	}
+}
+
+// failAfterNWriter wraps an io.Writer and fails once more than n bytes have
+// been written through it, to simulate a write failing partway.
+type failAfterNWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (f *failAfterNWriter) Write(p []byte) (int, error) {
+	if f.remaining <= 0 {
+		return 0, fmt.Errorf("synthetic write failure: byte limit exceeded")
+	}
+	if len(p) <= f.remaining {
+		n, err := f.w.Write(p)
+		f.remaining -= n
+		return n, err
+	}
+	n, err := f.w.Write(p[:f.remaining])
+	f.remaining -= n
+	if err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("synthetic write failure: byte limit exceeded")
+}
+
+func TestWriteAtomic_FailureLeavesOriginalUntouched(t *testing.T) {
+	testLog(t, ColorCyan, "--- START: TestWriteAtomic_FailureLeavesOriginalUntouched ---")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "target.log")
+	original := "original line 1\noriginal line 2\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Could not create test file: %v", err)
+	}
+
+	err := writeAtomic(path, true, func(w io.Writer) error {
+		fw := &failAfterNWriter{w: w, remaining: 5}
+		_, werr := io.WriteString(fw, "this replacement payload is longer than five bytes")
+		return werr
+	})
+	if err == nil {
+		testError(t, "✖ Expected writeAtomic to surface the synthetic write failure")
+	}
+
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		testError(t, fmt.Sprintf("✖ Original file missing after a failed write: %v", readErr))
+	} else if string(content) != original {
+		testError(t, fmt.Sprintf("✖ Original bytes were not preserved: got %q, want %q", content, original))
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".logcleaner-*"))
+	if len(leftovers) != 0 {
+		testError(t, fmt.Sprintf("✖ Temp file was not cleaned up after the failed write: %v", leftovers))
+	}
+
+	testLog(t, ColorGreen, "✔ A write failing partway left the original file's bytes exactly as they were.")
+}
+
 func equalSlices(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// expandTargets resolves a CLI path argument into the concrete list of log
+// files to process. It accepts a plain file path, a glob pattern (e.g.
+// "/var/log/app-*.log"), or a directory, in which case every "*.log" file
+// directly inside it is matched.
+func expandTargets(pattern string) ([]string, error) {
+	info, err := os.Stat(pattern)
+	if err == nil && info.IsDir() {
+		matches, err := filepath.Glob(filepath.Join(pattern, "*.log"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		return matches, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		// Not a glob (or nothing matched); treat the argument as a literal
+		// path and let cleanLog surface the "file not found" error.
+		return []string{pattern}, nil
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// runBatch applies base (with Path overridden per target) to every path in
+// targets, running at most jobs of them concurrently, and returns one error
+// per target in the same order (nil on success).
+func runBatch(targets []string, base CleanOptions, jobs int) []error {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+
+	for i, path := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			opts := base
+			opts.Path = path
+			errs[i] = cleanLog(opts)
+		}(i, path)
+	}
+
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Record is one structured log entry, as produced by a Parser.
+type Record struct {
+	Raw     string
+	Fields  map[string]string
+	Data    map[string]interface{}
+	Time    time.Time
+	HasTime bool
+}
+
+// Parser extracts a Record from a single line of a structured log. It
+// returns ok=false for a line that isn't the start of a new record (e.g. a
+// continuation line that doesn't parse), which the caller appends to the
+// preceding record instead.
+type Parser interface {
+	ParseLine(line []byte) (Record, bool)
+}
+
+// levelRank orders the standard level vocabulary for --min-level.
+var levelRank = map[string]int{
+	"trace": 0,
+	"debug": 1,
+	"info":  2,
+	"warn":  3,
+	"error": 4,
+	"fatal": 5,
+}
+
+type wherePredicate struct {
+	Key   string
+	Value string
+}
+
+type whereRegexPredicate struct {
+	Key     string
+	Pattern *regexp.Regexp
+}
+
+func parseWherePredicates(raw []string) ([]wherePredicate, error) {
+	preds := make([]wherePredicate, 0, len(raw))
+	for _, r := range raw {
+		idx := strings.Index(r, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid --where %q, expected key=value", r)
+		}
+		preds = append(preds, wherePredicate{Key: r[:idx], Value: r[idx+1:]})
+	}
+	return preds, nil
+}
+
+func parseWhereRegexPredicates(raw []string) ([]whereRegexPredicate, error) {
+	preds := make([]whereRegexPredicate, 0, len(raw))
+	for _, r := range raw {
+		idx := strings.Index(r, "=~")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid --where-regex %q, expected key=~pattern", r)
+		}
+		re, err := regexp.Compile(r[idx+2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --where-regex pattern in %q: %w", r, err)
+		}
+		preds = append(preds, whereRegexPredicate{Key: r[:idx], Pattern: re})
+	}
+	return preds, nil
+}
+
+func newParser(inputFormat, timeField, levelField string) (Parser, error) {
+	switch inputFormat {
+	case "json":
+		return jsonParser{timeField: timeField, levelField: levelField}, nil
+	case "logfmt":
+		return logfmtParser{timeField: timeField, levelField: levelField}, nil
+	default:
+		return nil, fmt.Errorf("unknown --input-format %q (want json or logfmt)", inputFormat)
+	}
+}
+
+type jsonParser struct {
+	timeField  string
+	levelField string
+}
+
+func (p jsonParser) ParseLine(line []byte) (Record, bool) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return Record{}, false
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(trimmed, &data); err != nil {
+		return Record{}, false
+	}
+	return recordFromData(string(line), data, p.timeField), true
+}
+
+type logfmtParser struct {
+	timeField  string
+	levelField string
+}
+
+func (p logfmtParser) ParseLine(line []byte) (Record, bool) {
+	fields := parseLogfmt(string(line))
+	if fields == nil {
+		return Record{}, false
+	}
+	data := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		data[k] = v
+	}
+	return recordFromData(string(line), data, p.timeField), true
+}
+
+// parseLogfmt tokenizes a logfmt line ("key=value key2=\"quoted value\"")
+// into a flat map. Tokenizing stops at the first token that is not a
+// key=value pair, so pairs after it are not included. It returns nil when
+// the line does not begin with a key=value pair, so the caller can treat the
+// line as a continuation of the previous record.
+func parseLogfmt(text string) map[string]string {
+	fields := map[string]string{}
+	i, n := 0, len(text)
+	for i < n {
+		for i < n && text[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < n && text[i] != '=' && text[i] != ' ' {
+			i++
+		}
+		if i >= n || text[i] != '=' {
+			break
+		}
+		key := text[start:i]
+		i++ // skip '='
+
+		var value string
+		if i < n && text[i] == '"' {
+			i++
+			valStart := i
+			for i < n && text[i] != '"' {
+				if text[i] == '\\' && i+1 < n {
+					i++
+				}
+				i++
+			}
+			value = text[valStart:i]
+			if i < n {
+				i++ // skip closing quote
+			}
+		} else {
+			valStart := i
+			for i < n && text[i] != ' ' {
+				i++
+			}
+			value = text[valStart:i]
+		}
+
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// structuredTimeLayouts are the timestamp layouts recognized in a
+// structured log's time field, tried in order.
+var structuredTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+// parseStructuredTime parses value against each of structuredTimeLayouts,
+// returning the first successful match.
+func parseStructuredTime(value string) (time.Time, bool) {
+	for _, layout := range structuredTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func recordFromData(raw string, data map[string]interface{}, timeField string) Record {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		fields[k] = fmt.Sprint(v)
+	}
+	rec := Record{Raw: raw, Fields: fields, Data: data}
+	if timeField != "" {
+		if v, ok := fields[timeField]; ok {
+			rec.Time, rec.HasTime = parseStructuredTime(v)
+		}
+	}
+	return rec
+}
+
+// cleanStructuredLog is cleanLog's counterpart for --input-format
+// json/logfmt: it parses each line into a Record instead of treating it as
+// an opaque string, filters by --min-level/--where/--where-regex instead of
+// --exclude, applies --date against --time-field's parsed timestamp instead
+// of the prefix/regex-based date filtering (requiring --time-field when
+// --date is set), and can re-emit compact JSON via --output-format json.
+func cleanStructuredLog(opts CleanOptions) error {
+	fmt.Printf("%s[INFO] Cleaning structured log: %s (max lines: %d)%s\n", ColorBold, opts.Path, opts.MaxRows, ColorReset)
+
+	if cacheHit(opts) {
+		return nil
+	}
+
+	readPath, backupPath, err := prepareBackup(opts)
+	if err != nil {
+		return err
+	}
+
+	var operationFailed bool
+	defer rollbackGuard(opts.Path, backupPath, opts.CompressBackup, &operationFailed)()
+
+	file, err := os.Open(readPath)
+	if err != nil {
+		operationFailed = true
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if !opts.DryRun && opts.CompressBackup {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			operationFailed = true
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var records []Record
+	scanner := bufio.NewScanner(reader)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if rec, ok := opts.Parser.ParseLine(line); ok {
+			records = append(records, rec)
+		} else if len(records) > 0 {
+			last := &records[len(records)-1]
+			last.Raw = last.Raw + " " + string(line)
+		}
+		// A continuation line seen before any record has parsed is dropped;
+		// there is nothing yet to attach it to.
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("%s[ERROR] Read failed: %v%s\n", ColorRed, err, ColorReset)
+		operationFailed = true
+		return err
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	// Filter by minimum level.
+	if opts.MinLevel != "" && opts.LevelField != "" {
+		minRank, ok := levelRank[strings.ToLower(opts.MinLevel)]
+		if !ok {
+			return fmt.Errorf("unknown --min-level %q", opts.MinLevel)
+		}
+		var filtered []Record
+		for _, rec := range records {
+			if rank, ok := levelRank[strings.ToLower(rec.Fields[opts.LevelField])]; ok && rank >= minRank {
+				filtered = append(filtered, rec)
+			}
+		}
+		records = filtered
+	}
+
+	// Filter by --where / --where-regex.
+	if len(opts.Where) > 0 || len(opts.WhereRegex) > 0 {
+		var filtered []Record
+		for _, rec := range records {
+			keep := true
+			for _, p := range opts.Where {
+				if rec.Fields[p.Key] != p.Value {
+					keep = false
+					break
+				}
+			}
+			if keep {
+				for _, p := range opts.WhereRegex {
+					if !p.Pattern.MatchString(rec.Fields[p.Key]) {
+						keep = false
+						break
+					}
+				}
+			}
+			if keep {
+				filtered = append(filtered, rec)
+			}
+		}
+		records = filtered
+	}
+
+	// Filter by date threshold, against --time-field's parsed timestamp
+	// rather than the prefix/regex-based parsing the plain-text pipeline
+	// uses.
+	if opts.MinDateStr != "" {
+		if opts.TimeField == "" {
+			return fmt.Errorf("--date requires --time-field in structured (--input-format json/logfmt) mode")
+		}
+		minDate, err := time.Parse(opts.DateFormat, opts.MinDateStr)
+		if err != nil {
+			return fmt.Errorf("could not parse --date '%s' with format '%s': %w", opts.MinDateStr, opts.DateFormat, err)
+		}
+		var filtered []Record
+		for _, rec := range records {
+			if rec.HasTime && !rec.Time.Before(minDate) {
+				filtered = append(filtered, rec)
+			}
+			// A record without a valid parsed timestamp is discarded when
+			// date filtering is active, matching the plain-text pipeline.
+		}
+		records = filtered
+	}
+
+	// Trimming (applied after filtering, same as the plain-text pipeline).
+	if len(records) > opts.MaxRows {
+		records = records[len(records)-opts.MaxRows:]
+	}
+
+	if opts.DryRun {
+		fmt.Printf("%s[DRY-RUN] %s would keep %d entries (no changes written)%s\n", ColorYellow, opts.Path, len(records), ColorReset)
+		return nil
+	}
+
+	err = writeAtomic(opts.Path, opts.Fsync, func(w io.Writer) error {
+		for _, rec := range records {
+			if opts.OutputFormat == "json" {
+				data := rec.Data
+				if data == nil {
+					data = map[string]interface{}{}
+				}
+				encoded, err := json.Marshal(data)
+				if err != nil {
+					return fmt.Errorf("could not encode record as json: %w", err)
+				}
+				if _, err := w.Write(encoded); err != nil {
+					return err
+				}
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			} else if _, err := io.WriteString(w, rec.Raw+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		operationFailed = true
+		return err
+	}
+
+	finishWrite(opts, len(records))
+
+	fmt.Printf("%s[OK] Log updated. Entries: %d%s\n", ColorGreen, len(records), ColorReset)
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+)
+
+const tailInitialChunkSize = 64 * 1024
+
+// parsesAsTimestamp reports whether line's prefix of length formatLen parses
+// as a valid timestamp under dateFormat.
+func parsesAsTimestamp(line, dateFormat string, formatLen int) bool {
+	if formatLen == 0 || len(line) < formatLen {
+		return false
+	}
+	_, err := time.Parse(dateFormat, line[:formatLen])
+	return err == nil
+}
+
+// tailEntries recovers the last maxRows log entries from the file at path
+// without reading it in full: it seeks to (end - chunkSize), doubling
+// chunkSize until it has collected at least maxRows+1 newlines or hit the
+// beginning of the file. When dateFormat is set, it keeps widening the
+// window further until the first complete line in it parses as a timestamp
+// and the window holds at least maxRows such entry headers, so a multiline
+// entry straddling the window boundary isn't misattributed as a new entry of
+// its own. Returns nil, nil for an empty file.
+func tailEntries(path string, maxRows int, dateFormat string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, nil
+	}
+
+	formatLen := len(dateFormat)
+	needed := int64(maxRows + 1)
+	chunkSize := int64(tailInitialChunkSize)
+
+	var buf []byte
+	var start int64
+
+	for {
+		start = size - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		atBOF := start == 0
+
+		buf = make([]byte, size-start)
+		if _, err := f.ReadAt(buf, start); err != nil {
+			return nil, err
+		}
+
+		if int64(bytes.Count(buf, []byte("\n"))) < needed && !atBOF {
+			chunkSize *= 2
+			continue
+		}
+
+		// The window's first line is a partial fragment of whatever
+		// preceded it (unless we're at the start of the file); the first
+		// complete entry begins after that fragment's newline.
+		firstComplete := buf
+		if !atBOF {
+			idx := bytes.IndexByte(buf, '\n')
+			if idx < 0 {
+				chunkSize *= 2
+				continue
+			}
+			firstComplete = buf[idx+1:]
+		}
+
+		if formatLen > 0 && !atBOF {
+			candidate := firstComplete
+			if idx := bytes.IndexByte(candidate, '\n'); idx >= 0 {
+				candidate = candidate[:idx]
+			}
+			if !parsesAsTimestamp(string(candidate), dateFormat, formatLen) {
+				chunkSize *= 2
+				continue
+			}
+			// maxRows+1 newlines guarantee maxRows lines, not maxRows
+			// multiline entries, so also count the entry headers.
+			headers := 0
+			for _, line := range strings.Split(string(firstComplete), "\n") {
+				if parsesAsTimestamp(line, dateFormat, formatLen) {
+					headers++
+				}
+			}
+			if headers < maxRows {
+				chunkSize *= 2
+				continue
+			}
+		}
+
+		break
+	}
+
+	text := string(buf)
+	if start > 0 {
+		// Drop the leading partial line picked up by the seek.
+		if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+			text = text[idx+1:]
+		} else {
+			text = ""
+		}
+	}
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+
+	var grouped []string
+	if formatLen > 0 {
+		for _, line := range lines {
+			if parsesAsTimestamp(line, dateFormat, formatLen) || len(grouped) == 0 {
+				grouped = append(grouped, line)
+			} else {
+				lastIdx := len(grouped) - 1
+				grouped[lastIdx] = grouped[lastIdx] + " " + line
+			}
+		}
+	} else {
+		grouped = lines
+	}
+
+	if len(grouped) > maxRows {
+		grouped = grouped[len(grouped)-maxRows:]
+	}
+	return grouped, nil
+}